@@ -0,0 +1,156 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package noderesourcetopology
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	topologyv1alpha1 "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha1"
+)
+
+const (
+	// PolicyAnnotation lets a pod request a NUMA topology admission policy
+	// that differs from the one the node's kubelet is configured with, as
+	// reported on its NodeResourceTopology. The requested policy may never be
+	// looser than the node's, and may be stricter only when the plugin is
+	// configured to allow looser nodes to host stricter pods.
+	PolicyAnnotation = "nodetopology.scheduler-plugins.x-k8s.io/numa-topology-policy"
+	// ScopeAnnotation selects whether PolicyAnnotation applies at pod or
+	// container granularity. If omitted, the node's own scope is kept.
+	ScopeAnnotation = "nodetopology.scheduler-plugins.x-k8s.io/numa-topology-scope"
+
+	policyValueSingleNUMANode = "single-numa-node"
+	policyValueRestricted     = "restricted"
+	policyValueBestEffort     = "best-effort"
+	policyValueNone           = "none"
+
+	scopeValuePod       = "pod"
+	scopeValueContainer = "container"
+)
+
+// policyRank orders TopologyManagerPolicy values from loosest (0) to
+// strictest, independent of scope, so a pod-requested policy can be checked
+// against the node's policy regardless of which scope each was expressed in.
+func policyRank(policy topologyv1alpha1.TopologyManagerPolicy) int {
+	switch policy {
+	case topologyv1alpha1.SingleNUMANodePodLevel, topologyv1alpha1.SingleNUMANodeContainerLevel:
+		return 3
+	case topologyv1alpha1.RestrictedPodLevel, topologyv1alpha1.RestrictedContainerLevel:
+		return 2
+	case topologyv1alpha1.BestEffortPodLevel, topologyv1alpha1.BestEffortContainerLevel:
+		return 1
+	default: // None, or anything we don't recognize
+		return 0
+	}
+}
+
+// podTopologyPolicyOverride parses PolicyAnnotation/ScopeAnnotation off pod,
+// returning ok=false when the pod carries no override.
+func podTopologyPolicyOverride(pod *v1.Pod, nodeScope topologyv1alpha1.TopologyManagerPolicy) (topologyv1alpha1.TopologyManagerPolicy, bool, error) {
+	policyValue, ok := pod.Annotations[PolicyAnnotation]
+	if !ok {
+		return "", false, nil
+	}
+
+	scopeValue := pod.Annotations[ScopeAnnotation]
+	if scopeValue == "" {
+		scopeValue = scopeOf(nodeScope)
+	}
+
+	policy, err := policyFromAnnotationValues(policyValue, scopeValue)
+	if err != nil {
+		return "", false, err
+	}
+	return policy, true, nil
+}
+
+// scopeOf returns the scope ("pod" or "container") encoded in policy.
+func scopeOf(policy topologyv1alpha1.TopologyManagerPolicy) string {
+	switch policy {
+	case topologyv1alpha1.SingleNUMANodeContainerLevel, topologyv1alpha1.RestrictedContainerLevel, topologyv1alpha1.BestEffortContainerLevel:
+		return scopeValueContainer
+	default:
+		return scopeValuePod
+	}
+}
+
+func policyFromAnnotationValues(policyValue, scopeValue string) (topologyv1alpha1.TopologyManagerPolicy, error) {
+	switch scopeValue {
+	case scopeValuePod:
+		switch policyValue {
+		case policyValueSingleNUMANode:
+			return topologyv1alpha1.SingleNUMANodePodLevel, nil
+		case policyValueRestricted:
+			return topologyv1alpha1.RestrictedPodLevel, nil
+		case policyValueBestEffort:
+			return topologyv1alpha1.BestEffortPodLevel, nil
+		case policyValueNone:
+			return topologyv1alpha1.None, nil
+		}
+	case scopeValueContainer:
+		switch policyValue {
+		case policyValueSingleNUMANode:
+			return topologyv1alpha1.SingleNUMANodeContainerLevel, nil
+		case policyValueRestricted:
+			return topologyv1alpha1.RestrictedContainerLevel, nil
+		case policyValueBestEffort:
+			return topologyv1alpha1.BestEffortContainerLevel, nil
+		case policyValueNone:
+			return topologyv1alpha1.None, nil
+		}
+	default:
+		return "", fmt.Errorf("unknown value %q for annotation %q", scopeValue, ScopeAnnotation)
+	}
+	return "", fmt.Errorf("unknown value %q for annotation %q", policyValue, PolicyAnnotation)
+}
+
+// resolveEffectivePolicy computes the policy this plugin should use to admit
+// pod on a node whose NodeResourceTopology reports nodePolicy, applying any
+// pod-level override and rejecting overrides the node cannot honor in
+// either direction: a pod may not demand stricter alignment than the node's
+// kubelet actually enforces (unless allowStricterThanNode says otherwise —
+// see TopologyMatch.allowStricterPodPolicy for why callers need to opt into
+// this explicitly), and it may not claim looser alignment either, since the
+// kubelet enforces its own configured policy regardless of what this plugin
+// admits against — a pod let through on a fictitiously relaxed policy would
+// pass Filter/Score here and still fail admission (or get a worse placement
+// than expected) on the node itself.
+func resolveEffectivePolicy(pod *v1.Pod, nodePolicy topologyv1alpha1.TopologyManagerPolicy, allowStricterThanNode bool) (topologyv1alpha1.TopologyManagerPolicy, *framework.Status) {
+	podPolicy, ok, err := podTopologyPolicyOverride(pod, nodePolicy)
+	if err != nil {
+		return "", framework.NewStatus(framework.UnschedulableAndUnresolvable, err.Error())
+	}
+	if !ok {
+		return nodePolicy, nil
+	}
+
+	if policyRank(podPolicy) > policyRank(nodePolicy) && !allowStricterThanNode {
+		return "", framework.NewStatus(framework.Unschedulable, fmt.Sprintf(
+			"pod requests topology policy %q, stricter than node policy %q", podPolicy, nodePolicy))
+	}
+
+	if policyRank(podPolicy) < policyRank(nodePolicy) {
+		return "", framework.NewStatus(framework.Unschedulable, fmt.Sprintf(
+			"pod requests topology policy %q, looser than node policy %q which its kubelet enforces regardless",
+			podPolicy, nodePolicy))
+	}
+
+	return podPolicy, nil
+}
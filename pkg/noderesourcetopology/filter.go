@@ -0,0 +1,91 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package noderesourcetopology
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	topologyv1alpha1 "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha1"
+)
+
+var _ framework.FilterPlugin = &TopologyMatch{}
+
+// Filter rejects a node whose NodeResourceTopology cannot satisfy pod under
+// the effective topology policy, i.e. the pod's own override (if any),
+// resolved against the policy reported by the node.
+func (tm *TopologyMatch) Filter(ctx context.Context, cycleState *framework.CycleState, pod *v1.Pod, nodeInfo *framework.NodeInfo) *framework.Status {
+	if nodeInfo.Node() == nil {
+		return framework.NewStatus(framework.Error, "node not found")
+	}
+	nodeName := nodeInfo.Node().Name
+
+	nrt, ok := tm.nrtCache.GetCachedNRTCopy(nodeName, pod)
+	if !ok || nrt == nil {
+		// no topology information for this node: defer to other plugins
+		return nil
+	}
+
+	nodePolicy := nodeTopologyPolicy(nrt)
+	effectivePolicy, status := resolveEffectivePolicy(pod, nodePolicy, tm.allowStricterPodPolicy)
+	if status != nil {
+		return status
+	}
+
+	handler, ok := tm.filterHandlers[effectivePolicy]
+	if !ok {
+		// node doesn't run with a policy this plugin knows how to reason about
+		return nil
+	}
+
+	zones := tm.zonesMatchingRequiredAttribute(nrt.Zones)
+	if len(zones) == 0 {
+		return framework.NewStatus(framework.Unschedulable, "no NUMA zone on this node carries the required attribute")
+	}
+
+	pool := qosResourcePool(pod, tm.qosResourcePoolRules)
+	return handler(pod, zones, nodeInfo, pool)
+}
+
+// zonesMatchingRequiredAttribute filters zones down to the ones that carry
+// tm.requiredZoneAttribute, so a pod can never be admitted onto a zone that
+// doesn't satisfy the configured Attributes matcher. With no matcher
+// configured, zones is returned unchanged.
+func (tm *TopologyMatch) zonesMatchingRequiredAttribute(zones ZoneList) ZoneList {
+	if tm.requiredZoneAttribute.key == "" {
+		return zones
+	}
+
+	matching := make(ZoneList, 0, len(zones))
+	for _, zone := range zones {
+		if zone.Attributes[tm.requiredZoneAttribute.key] == tm.requiredZoneAttribute.value {
+			matching = append(matching, zone)
+		}
+	}
+	return matching
+}
+
+// nodeTopologyPolicy returns the TopologyManagerPolicy the node's kubelet is
+// actually configured with, as advertised on its NodeResourceTopology.
+func nodeTopologyPolicy(nrt *NodeResourceTopology) topologyv1alpha1.TopologyManagerPolicy {
+	if len(nrt.TopologyPolicies) == 0 || nrt.TopologyPolicies[0] == "" {
+		return topologyv1alpha1.None
+	}
+	return topologyv1alpha1.TopologyManagerPolicy(nrt.TopologyPolicies[0])
+}
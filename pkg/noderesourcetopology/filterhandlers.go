@@ -0,0 +1,103 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package noderesourcetopology
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	topologyv1alpha1 "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha1"
+)
+
+// newFilterHandlers returns the per-policy Filter handlers. Pod-level
+// policies reason about the pod's combined request: SingleNUMANode demands
+// one zone hold the whole request, while Restricted/BestEffort may spread it
+// across every zone the node has. Container-level policies, whichever of the
+// three, check each container on its own and demand that one zone hold that
+// container's whole request. All of them account the request
+// against pod's resolved resourcePool, so a reclaimed pod never admits by
+// spending a guaranteed pod's capacity.
+func newFilterHandlers() filterHandlersMap {
+	return filterHandlersMap{
+		topologyv1alpha1.SingleNUMANodePodLevel:       singleZoneFilter,
+		topologyv1alpha1.RestrictedPodLevel:           spanningZonesFilter,
+		topologyv1alpha1.BestEffortPodLevel:           spanningZonesFilter,
+		topologyv1alpha1.SingleNUMANodeContainerLevel: perContainerSingleZoneFilter,
+		topologyv1alpha1.RestrictedContainerLevel:     perContainerSingleZoneFilter,
+		topologyv1alpha1.BestEffortContainerLevel:     perContainerSingleZoneFilter,
+	}
+}
+
+// singleZoneFilter rejects the node unless one of its NUMA zones, alone, can
+// hold the pod's combined request out of pool.
+func singleZoneFilter(pod *v1.Pod, zones ZoneList, nodeInfo *framework.NodeInfo, pool resourcePool) *framework.Status {
+	numaNodes := newNUMANodeList(nodeScopedZones(zones))
+	requested := totalContainerRequests(pod)
+	for i := range numaNodes {
+		if len(subtractFromNUMAs(requested, numaNodes, pool, i)) == 0 {
+			return nil
+		}
+	}
+	return framework.NewStatus(framework.Unschedulable, "no single NUMA zone can satisfy this pod's request in its resource pool")
+}
+
+// spanningZonesFilter rejects the node unless its NUMA zones, taken
+// together, can hold the pod's combined request out of pool.
+func spanningZonesFilter(pod *v1.Pod, zones ZoneList, nodeInfo *framework.NodeInfo, pool resourcePool) *framework.Status {
+	numaNodes := newNUMANodeList(nodeScopedZones(zones))
+	requested := totalContainerRequests(pod)
+
+	allNodes := make([]int, len(numaNodes))
+	for i := range numaNodes {
+		allNodes[i] = i
+	}
+
+	if len(subtractFromNUMAs(requested, numaNodes, pool, allNodes...)) > 0 {
+		return framework.NewStatus(framework.Unschedulable, "this node's NUMA zones cannot satisfy this pod's request in its resource pool")
+	}
+	return nil
+}
+
+// perContainerSingleZoneFilter rejects the node unless every container can
+// be placed, one at a time, in some single NUMA zone that still has room for
+// it once earlier containers in the pod have depleted their own chosen
+// zone — the same sequential accounting Reserve performs, run here as a dry
+// run so Filter catches what would otherwise only surface as a Reserve
+// failure after binding. Each zone tried for a container is a throwaway
+// copy: subtractFromNUMAs mutates on a failed attempt too (it zeroes
+// whatever it did consume before discovering the rest doesn't fit), so
+// trying zone N for container A must not touch the real numaNodes unless A
+// actually lands there, or a later container could wrongly see zone N as
+// already drained by an attempt that never happened.
+func perContainerSingleZoneFilter(pod *v1.Pod, zones ZoneList, nodeInfo *framework.NodeInfo, pool resourcePool) *framework.Status {
+	numaNodes := newNUMANodeList(nodeScopedZones(zones))
+	for _, container := range pod.Spec.Containers {
+		placed := false
+		for i := range numaNodes {
+			attempt := numaNodes.DeepCopy()
+			if len(subtractFromNUMAs(container.Resources.Requests, attempt, pool, i)) == 0 {
+				numaNodes = attempt
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			return framework.NewStatus(framework.Unschedulable, "no single NUMA zone can satisfy every container of this pod in its resource pool")
+		}
+	}
+	return nil
+}
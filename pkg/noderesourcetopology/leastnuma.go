@@ -0,0 +1,208 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package noderesourcetopology
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// leastNUMAPodScopeScore prefers nodes where the pod's total request fits in
+// the fewest NUMA zones; among zone sets that tie on count, it prefers the
+// one with the lowest aggregate v1alpha2 Costs, since that's the cheaper
+// memory/interconnect path for a pod whose containers will be spread across
+// those zones.
+func leastNUMAPodScopeScore(pod *v1.Pod, zones ZoneList, pool resourcePool) (int64, *framework.Status) {
+	return leastNUMAScore(totalContainerRequests(pod), zones, pool)
+}
+
+// leastNUMAContainerScopeScore is the container-scope counterpart: with
+// container-level alignment each container picks its own zone
+// independently, so what matters is the zone count the pod's single
+// largest container needs.
+func leastNUMAContainerScopeScore(pod *v1.Pod, zones ZoneList, pool resourcePool) (int64, *framework.Status) {
+	return leastNUMAScore(largestContainerRequests(pod), zones, pool)
+}
+
+// leastNUMAScore scores a node by how many of its zones requested needs out
+// of pool, and breaks ties between equally-sized zone sets by aggregate
+// cross-zone cost.
+func leastNUMAScore(requested v1.ResourceList, zones ZoneList, pool resourcePool) (int64, *framework.Status) {
+	nodeZones := nodeScopedZones(zones)
+	// keep only the zones newNUMANodeList turns into a NUMANode, so nodeZones
+	// and numaNodes stay index-aligned for leastZonesFitting.
+	aligned := make(ZoneList, 0, len(nodeZones))
+	for _, zone := range nodeZones {
+		if _, err := numaIDFromZoneName(zone.Name); err == nil {
+			aligned = append(aligned, zone)
+		}
+	}
+	nodeZones = aligned
+	if len(nodeZones) == 0 {
+		return 0, framework.NewStatus(framework.Unschedulable, "no NUMA zones reported for this node")
+	}
+	numaNodes := newNUMANodeList(nodeZones)
+
+	best := leastZonesFitting(requested, numaNodes, nodeZones, pool)
+	if best == nil {
+		return 0, framework.NewStatus(framework.Unschedulable, "no combination of NUMA zones can satisfy the request")
+	}
+
+	// fewer zones is always better: 100 divided by the node's own zone count
+	// is lost per extra zone needed.
+	spacing := int64(100) / int64(len(nodeZones))
+	score := int64(100) - int64(len(best)-1)*spacing
+
+	if len(best) > 1 {
+		// cost only ever breaks a tie between zone sets of the same size,
+		// so it is capped at 10 and below the per-zone spacing above.
+		cost := int64(crossZoneCost(nodeZones, best))
+		maxCost := spacing - 1
+		if maxCost > 10 {
+			maxCost = 10
+		}
+		if cost > maxCost {
+			cost = maxCost
+		}
+		if cost > 0 {
+			score -= cost
+		}
+	}
+
+	if score < 0 {
+		score = 0
+	}
+	return score, nil
+}
+
+// nodeScopedZones filters zones down to the ones representing an actual NUMA
+// node, skipping any other granularity a future NRT might report.
+func nodeScopedZones(zones ZoneList) ZoneList {
+	nodeZones := make(ZoneList, 0, len(zones))
+	for _, zone := range zones {
+		if zone.Type == "Node" {
+			nodeZones = append(nodeZones, zone)
+		}
+	}
+	return nodeZones
+}
+
+// leastZonesFitting returns the names of the smallest set of numaNodes whose
+// combined pool capacity satisfies requested, or nil if no combination does.
+// When several combinations of the winning size fit, it returns the one with
+// the lowest aggregate cross-zone cost, read off zones (the same node
+// zones numaNodes was built from, in the same order).
+func leastZonesFitting(requested v1.ResourceList, numaNodes NUMANodeList, zones ZoneList, pool resourcePool) []string {
+	for size := 1; size <= len(numaNodes); size++ {
+		var (
+			bestCombo []string
+			bestCost  = -1
+		)
+		forEachCombination(len(numaNodes), size, func(indices []int) {
+			if !combinationFits(requested, numaNodes, pool, indices) {
+				return
+			}
+			combo := zoneNamesAt(zones, indices)
+			cost := crossZoneCost(zones, combo)
+			if bestCost == -1 || cost < bestCost {
+				bestCost = cost
+				bestCombo = combo
+			}
+		})
+		if bestCombo != nil {
+			return bestCombo
+		}
+	}
+	return nil
+}
+
+func zoneNamesAt(zones ZoneList, indices []int) []string {
+	names := make([]string, len(indices))
+	for i, idx := range indices {
+		names[i] = zones[idx].Name
+	}
+	return names
+}
+
+// combinationFits reports whether the numaNodes at indices, summed together,
+// have enough of every requested resource in pool.
+func combinationFits(requested v1.ResourceList, numaNodes NUMANodeList, pool resourcePool, indices []int) bool {
+	available := v1.ResourceList{}
+	for _, idx := range indices {
+		for resName, qty := range numaNodes[idx].resourcesOf(pool) {
+			if existing, ok := available[resName]; ok {
+				existing.Add(qty)
+				available[resName] = existing
+			} else {
+				available[resName] = qty.DeepCopy()
+			}
+		}
+	}
+
+	for resName, reqQty := range requested {
+		availQty, ok := available[resName]
+		if !ok || reqQty.Cmp(availQty) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// forEachCombination calls visit with every size-length subset of indices
+// into [0, n), as an increasing slice of indices, smallest first. n is the
+// NUMA zone count of a single node, so this is always small in practice.
+func forEachCombination(n, size int, visit func(indices []int)) {
+	if size > n {
+		return
+	}
+	indices := make([]int, size)
+	for i := range indices {
+		indices[i] = i
+	}
+
+	for {
+		visit(append([]int(nil), indices...))
+
+		i := size - 1
+		for i >= 0 && indices[i] == i+n-size {
+			i--
+		}
+		if i < 0 {
+			return
+		}
+		indices[i]++
+		for j := i + 1; j < size; j++ {
+			indices[j] = indices[j-1] + 1
+		}
+	}
+}
+
+// largestContainerRequests returns the Requests of pod's most CPU-hungry
+// container, the one that drives how many zones a container-scoped policy
+// needs.
+func largestContainerRequests(pod *v1.Pod) v1.ResourceList {
+	var largest v1.ResourceList
+	var largestCPU int64
+	for _, container := range pod.Spec.Containers {
+		cpu := container.Resources.Requests.Cpu().MilliValue()
+		if largest == nil || cpu > largestCPU {
+			largest = container.Resources.Requests
+			largestCPU = cpu
+		}
+	}
+	return largest
+}
@@ -40,19 +40,71 @@ const (
 type NUMANode struct {
 	NUMAID    int
 	Resources v1.ResourceList
+	// ReclaimedResources holds the Katalyst-style best-effort pool
+	// (katalyst.kubewharf.io/reclaimed_<resource>) a zone advertises
+	// alongside its guaranteed Resources, keyed by the same base resource
+	// name. It is nil for zones that don't expose any reclaimed resources.
+	ReclaimedResources v1.ResourceList
 }
 
 type NUMANodeList []NUMANode
 
-func subtractFromNUMAs(resources v1.ResourceList, numaNodes NUMANodeList, nodes ...int) {
+// DeepCopy returns a copy of n whose Resources/ReclaimedResources maps are
+// independent of n's, so a caller can try depleting the copy (e.g. to test
+// whether a candidate zone fits a request) without corrupting n on a failed
+// attempt.
+func (n NUMANodeList) DeepCopy() NUMANodeList {
+	out := make(NUMANodeList, len(n))
+	for i, node := range n {
+		out[i] = NUMANode{
+			NUMAID:             node.NUMAID,
+			Resources:          node.Resources.DeepCopy(),
+			ReclaimedResources: node.ReclaimedResources.DeepCopy(),
+		}
+	}
+	return out
+}
+
+// resourcePool selects which per-zone resource pool a pod's containers
+// should be accounted against.
+type resourcePool int
+
+const (
+	// guaranteedPool is the pool every pod was accounted against before
+	// reclaimed resources existed, and remains the default.
+	guaranteedPool resourcePool = iota
+	// reclaimedPool is the Katalyst best-effort pool: on a zone that
+	// advertises reclaimed resources it is capped by that reclaimed capacity
+	// and never competes with guaranteedPool; on a zone that advertises none,
+	// resourcesOf falls back to the guaranteed pool.
+	reclaimedPool
+)
+
+// resourcesOf returns the NUMANode's resource pool for the selected class,
+// falling back to the guaranteed pool if a zone exposes no reclaimed
+// resources at all (so unknown/legacy NRTs keep behaving as before).
+func (n NUMANode) resourcesOf(pool resourcePool) v1.ResourceList {
+	if pool == reclaimedPool && n.ReclaimedResources != nil {
+		return n.ReclaimedResources
+	}
+	return n.Resources
+}
+
+// subtractFromNUMAs depletes nodes' pool-selected resource pool by
+// resources, in order, and returns whatever of resources none of them had
+// enough of. An empty return means resources fit entirely within nodes, in
+// that pool; callers use that as the fit check.
+func subtractFromNUMAs(resources v1.ResourceList, numaNodes NUMANodeList, pool resourcePool, nodes ...int) v1.ResourceList {
+	leftover := v1.ResourceList{}
 	for resName, quantity := range resources {
+		quantity = quantity.DeepCopy()
 		for _, node := range nodes {
 			// quantity is zero no need to iterate through another NUMA node, go to another resource
 			if quantity.IsZero() {
 				break
 			}
 
-			nRes := numaNodes[node].Resources
+			nRes := numaNodes[node].resourcesOf(pool)
 			if available, ok := nRes[resName]; ok {
 				switch quantity.Cmp(available) {
 				case 0: // the same
@@ -74,11 +126,15 @@ func subtractFromNUMAs(resources v1.ResourceList, numaNodes NUMANodeList, nodes
 				}
 			}
 		}
+		if !quantity.IsZero() {
+			leftover[resName] = quantity
+		}
 	}
+	return leftover
 }
 
-type filterFn func(pod *v1.Pod, zones topologyv1alpha1.ZoneList, nodeInfo *framework.NodeInfo) *framework.Status
-type scoringFn func(*v1.Pod, topologyv1alpha1.ZoneList) (int64, *framework.Status)
+type filterFn func(pod *v1.Pod, zones ZoneList, nodeInfo *framework.NodeInfo, pool resourcePool) *framework.Status
+type scoringFn func(pod *v1.Pod, zones ZoneList, pool resourcePool) (int64, *framework.Status)
 
 type filterHandlersMap map[topologyv1alpha1.TopologyManagerPolicy]filterFn
 type scoreHandlersMap map[topologyv1alpha1.TopologyManagerPolicy]scoringFn
@@ -99,7 +155,35 @@ type TopologyMatch struct {
 	filterHandlers      filterHandlersMap
 	scoringHandlers     scoreHandlersMap
 	resourceToWeightMap resourceToWeightMap
-	nrtCache            nrtcache.Interface
+	// nrtCache must implement GetCachedNRTCopy(nodeName string, pod *v1.Pod)
+	// (*NodeResourceTopology, bool), ReserveNodeResources(nodeName string,
+	// pod *v1.Pod, policy topologyv1alpha1.TopologyManagerPolicy, pool
+	// resourcePool) error, UnreserveNodeResources(nodeName string, pod
+	// *v1.Pod) error, and List() []*NodeResourceTopology; Filter, Score,
+	// Reserve/Unreserve and PreEnqueue all call it against this contract.
+	nrtCache nrtcache.Interface
+	// allowStricterPodPolicy, when true, lets a pod's PolicyAnnotation
+	// request a topology policy stricter than the node's own, even though
+	// the node's kubelet cannot actually guarantee it. PolicyAnnotation can
+	// never loosen a node's policy (the kubelet enforces its own
+	// configuration regardless of what this plugin admits against), so with
+	// this left false — the zero value, and what NodeResourceTopologyMatchArgs
+	// defaults to when an operator doesn't set
+	// AllowStricterPodTopologyPolicy — the only override that survives
+	// resolveEffectivePolicy is one at the node's own strictness, so
+	// PolicyAnnotation can then change only the scope (pod or container, via
+	// ScopeAnnotation), not the policy. Operators who want pods to request
+	// stricter-than-node alignment (the feature's actual purpose: running
+	// mixed-strictness workloads without reconfiguring every node's kubelet)
+	// must explicitly set AllowStricterPodTopologyPolicy: true.
+	allowStricterPodPolicy bool
+	// qosResourcePoolRules declares which pod QoS classes / priority
+	// classes consume the zone's reclaimed resource pool instead of its
+	// guaranteed one. Pods matching no rule use the guaranteed pool.
+	qosResourcePoolRules []apiconfig.QoSResourcePoolRule
+	// requiredZoneAttribute, when key is non-empty, restricts admission to
+	// zones whose v1alpha2 Attributes carry this exact key/value pair.
+	requiredZoneAttribute struct{ key, value string }
 }
 
 var _ framework.FilterPlugin = &TopologyMatch{}
@@ -133,9 +217,16 @@ func New(args runtime.Object, handle framework.Handle) (framework.Plugin, error)
 
 	var scoringHandlers scoreHandlersMap
 
-	if tcfg.ScoringStrategy.Type == apiconfig.LeastNUMANodes {
+	switch tcfg.ScoringStrategy.Type {
+	case apiconfig.LeastNUMANodes:
 		scoringHandlers = leastNUMAscoreHandlers()
-	} else {
+	case apiconfig.RequestedToCapacityRatio:
+		shape := tcfg.ScoringStrategy.RequestedToCapacityRatio.Shape
+		if err := validateRequestedToCapacityRatioShape(shape); err != nil {
+			return nil, err
+		}
+		scoringHandlers = newRequestedToCapacityRatioScoreHandlers(shape, resToWeightMap)
+	default:
 		strategy, err := getScoringStrategyFunction(tcfg.ScoringStrategy.Type)
 		if err != nil {
 			return nil, err
@@ -145,10 +236,21 @@ func New(args runtime.Object, handle framework.Handle) (framework.Plugin, error)
 	}
 
 	topologyMatch := &TopologyMatch{
-		filterHandlers:      newFilterHandlers(),
-		scoringHandlers:     scoringHandlers,
-		resourceToWeightMap: resToWeightMap,
-		nrtCache:            nrtCache,
+		filterHandlers:         newFilterHandlers(),
+		scoringHandlers:        scoringHandlers,
+		resourceToWeightMap:    resToWeightMap,
+		nrtCache:               nrtCache,
+		allowStricterPodPolicy: tcfg.AllowStricterPodTopologyPolicy,
+		qosResourcePoolRules:   tcfg.QoSResourcePools,
+	}
+
+	if tcfg.RequiredZoneAttribute != "" {
+		key, value, err := parseZoneAttributeMatch(tcfg.RequiredZoneAttribute)
+		if err != nil {
+			return nil, err
+		}
+		topologyMatch.requiredZoneAttribute.key = key
+		topologyMatch.requiredZoneAttribute.value = value
 	}
 
 	return topologyMatch, nil
@@ -159,13 +261,21 @@ func New(args runtime.Object, handle framework.Handle) (framework.Plugin, error)
 // NOTE: if in-place-update (KEP 1287) gets implemented, then PodUpdate event
 // should be registered for this plugin since a Pod update may free up resources
 // that make other Pods schedulable.
-func (tm *TopologyMatch) EventsToRegister() []framework.ClusterEvent {
+func (tm *TopologyMatch) EventsToRegister() []framework.ClusterEventWithHint {
 	// To register a custom event, follow the naming convention at:
 	// https://git.k8s.io/kubernetes/pkg/scheduler/eventhandlers.go#L403-L410
-	nrtGVK := fmt.Sprintf("noderesourcetopologies.v1alpha1.%v", topologyapi.GroupName)
-	return []framework.ClusterEvent{
-		{Resource: framework.Pod, ActionType: framework.Delete},
-		{Resource: framework.Node, ActionType: framework.Add | framework.UpdateNodeAllocatable},
-		{Resource: framework.GVK(nrtGVK), ActionType: framework.Add | framework.Update},
+	nrtV1alpha1GVK := fmt.Sprintf("noderesourcetopologies.v1alpha1.%v", topologyapi.GroupName)
+	nrtV1alpha2GVK := fmt.Sprintf("noderesourcetopologies.v1alpha2.%v", topologyapi.GroupName)
+	return []framework.ClusterEventWithHint{
+		{Event: framework.ClusterEvent{Resource: framework.Pod, ActionType: framework.Delete}},
+		{Event: framework.ClusterEvent{Resource: framework.Node, ActionType: framework.Add | framework.UpdateNodeAllocatable}},
+		{
+			Event:          framework.ClusterEvent{Resource: framework.GVK(nrtV1alpha1GVK), ActionType: framework.Add | framework.Update},
+			QueueingHintFn: tm.isSchedulableAfterNRTChange,
+		},
+		{
+			Event:          framework.ClusterEvent{Resource: framework.GVK(nrtV1alpha2GVK), ActionType: framework.Add | framework.Update},
+			QueueingHintFn: tm.isSchedulableAfterNRTChange,
+		},
 	}
 }
@@ -0,0 +1,191 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package noderesourcetopology
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+
+	apiconfig "sigs.k8s.io/scheduler-plugins/apis/config"
+)
+
+// ReclaimedResourcePrefix marks a Katalyst-style reclaimed resource name,
+// e.g. "katalyst.kubewharf.io/reclaimed_cpu" pools alongside plain "cpu".
+const ReclaimedResourcePrefix = "katalyst.kubewharf.io/reclaimed_"
+
+// splitReclaimedResourceName returns the guaranteed-pool resource name a
+// reclaimed resource corresponds to, and whether name was a reclaimed one at
+// all. Resource names that don't carry the prefix are left untouched:
+// unknown pools continue to behave as today.
+func splitReclaimedResourceName(name v1.ResourceName) (v1.ResourceName, bool) {
+	base := strings.TrimPrefix(string(name), ReclaimedResourcePrefix)
+	if base == string(name) {
+		return name, false
+	}
+	return v1.ResourceName(base), true
+}
+
+// newNUMANodeList converts a NRT's zones into a NUMANodeList, splitting out
+// any reclaimed resources a zone advertises into NUMANode.ReclaimedResources
+// so guaranteed and reclaimed pods are accounted against separate capacity.
+func newNUMANodeList(zones ZoneList) NUMANodeList {
+	numaNodes := make(NUMANodeList, 0, len(zones))
+	for _, zone := range zones {
+		if zone.Type != "Node" {
+			continue
+		}
+
+		numaID, err := numaIDFromZoneName(zone.Name)
+		if err != nil {
+			continue
+		}
+
+		node := NUMANode{NUMAID: numaID, Resources: v1.ResourceList{}}
+		for _, res := range zone.Resources {
+			if baseName, isReclaimed := splitReclaimedResourceName(v1.ResourceName(res.Name)); isReclaimed {
+				if node.ReclaimedResources == nil {
+					node.ReclaimedResources = v1.ResourceList{}
+				}
+				node.ReclaimedResources[baseName] = res.Available
+				continue
+			}
+			node.Resources[v1.ResourceName(res.Name)] = res.Available
+		}
+		numaNodes = append(numaNodes, node)
+	}
+	return numaNodes
+}
+
+// qosResourcePool resolves which resource pool a pod's containers should be
+// accounted against, consulting the QoS/priority-class mapping declared in
+// NodeResourceTopologyMatchArgs. Pods that match no rule keep using the
+// guaranteed pool, preserving today's behavior.
+func qosResourcePool(pod *v1.Pod, rules []apiconfig.QoSResourcePoolRule) resourcePool {
+	for _, rule := range rules {
+		if rule.PriorityClassName != "" && rule.PriorityClassName == pod.Spec.PriorityClassName {
+			return poolFromName(rule.Pool)
+		}
+		if rule.QoSClass != "" && string(rule.QoSClass) == string(podQOSClass(pod)) {
+			return poolFromName(rule.Pool)
+		}
+	}
+	return guaranteedPool
+}
+
+func poolFromName(name string) resourcePool {
+	if name == "reclaimed" {
+		return reclaimedPool
+	}
+	return guaranteedPool
+}
+
+// numaIDFromZoneName parses the NUMA ID out of a zone name of the form
+// "node-<id>", the convention k8stopologyawareschedwg's NRT informers use.
+func numaIDFromZoneName(name string) (int, error) {
+	parts := strings.SplitN(name, "-", 2)
+	if len(parts) != 2 {
+		return -1, fmt.Errorf("invalid zone name %q, expected \"node-<id>\"", name)
+	}
+	return strconv.Atoi(parts[1])
+}
+
+// podQOSClass reports pod's QoS class. pod.Status.QOSClass is trusted when
+// set, but qosResourcePool also has to classify pods the apiserver hasn't
+// stamped yet (e.g. in Filter/Score, which see the pod as submitted, not as
+// defaulted), so this otherwise mirrors v1qos.GetPodQOS: Guaranteed requires
+// every container to set both cpu and memory limits with requests equal to
+// those limits; BestEffort is no container setting any requests or limits
+// at all; everything else is Burstable.
+func podQOSClass(pod *v1.Pod) v1.PodQOSClass {
+	if pod.Status.QOSClass != "" {
+		return pod.Status.QOSClass
+	}
+
+	requests := v1.ResourceList{}
+	limits := v1.ResourceList{}
+	isGuaranteed := true
+
+	allContainers := make([]v1.Container, 0, len(pod.Spec.Containers)+len(pod.Spec.InitContainers))
+	allContainers = append(allContainers, pod.Spec.Containers...)
+	allContainers = append(allContainers, pod.Spec.InitContainers...)
+
+	for _, container := range allContainers {
+		addQoSComputeResources(requests, container.Resources.Requests)
+
+		containerLimits := container.Resources.Limits
+		hasCPULimit := isResourceSet(containerLimits, v1.ResourceCPU)
+		hasMemoryLimit := isResourceSet(containerLimits, v1.ResourceMemory)
+		if !hasCPULimit || !hasMemoryLimit {
+			isGuaranteed = false
+		}
+		addQoSComputeResources(limits, containerLimits)
+	}
+
+	if len(requests) == 0 && len(limits) == 0 {
+		return v1.PodQOSBestEffort
+	}
+
+	if isGuaranteed {
+		if len(requests) != len(limits) {
+			isGuaranteed = false
+		} else {
+			for name, reqQty := range requests {
+				if limQty, ok := limits[name]; !ok || limQty.Cmp(reqQty) != 0 {
+					isGuaranteed = false
+					break
+				}
+			}
+		}
+	}
+
+	if isGuaranteed {
+		return v1.PodQOSGuaranteed
+	}
+	return v1.PodQOSBurstable
+}
+
+// qosComputeResources are the only resources GetPodQOS-style classification
+// considers; everything else (ephemeral-storage, extended resources, ...)
+// is irrelevant to QoS.
+var qosComputeResources = map[v1.ResourceName]bool{
+	v1.ResourceCPU:    true,
+	v1.ResourceMemory: true,
+}
+
+// isResourceSet reports whether list declares a positive quantity for name.
+func isResourceSet(list v1.ResourceList, name v1.ResourceName) bool {
+	qty, ok := list[name]
+	return ok && qty.Sign() > 0
+}
+
+// addQoSComputeResources accumulates the cpu/memory quantities in from into
+// total, skipping zero quantities and any resource GetPodQOS doesn't weigh.
+func addQoSComputeResources(total, from v1.ResourceList) {
+	for name, qty := range from {
+		if !qosComputeResources[name] || qty.Sign() <= 0 {
+			continue
+		}
+		sum := qty.DeepCopy()
+		if existing, ok := total[name]; ok {
+			sum.Add(existing)
+		}
+		total[name] = sum
+	}
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package noderesourcetopology
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+var _ framework.PreEnqueuePlugin = &TopologyMatch{}
+
+// PreEnqueue runs before pod enters activeQ. For pods whose own
+// PolicyAnnotation requests NUMA alignment (single-numa-node/restricted), it
+// does a cheap cluster-wide check against the cached NodeResourceTopology
+// objects: if no zone anywhere can possibly host the pod's largest container
+// (container scope) or total request (pod scope), there is no point letting
+// the pod cycle through Filter on every node, so it is parked as
+// Unschedulable until an NRT update wakes it via the QueueingHintFn
+// registered in EventsToRegister. The nodes' own policies are not consulted:
+// a pod without such an annotation always passes, whatever its nodes run.
+func (tm *TopologyMatch) PreEnqueue(ctx context.Context, pod *v1.Pod) *framework.Status {
+	if !requiresNUMAAlignment(pod) {
+		return nil
+	}
+
+	nrts := tm.nrtCache.List()
+	for _, nrt := range nrts {
+		if tm.anyNodeFits(pod, nrt) {
+			return nil
+		}
+	}
+
+	return framework.NewStatus(framework.Unschedulable, "no NUMA zone in the cluster can satisfy this pod's topology policy")
+}
+
+// requiresNUMAAlignment reports whether pod's own PolicyAnnotation demands a
+// NUMA-aligned zone. The node's own policy is only known once Filter has a
+// specific node's NRT in hand, so PreEnqueue — which runs before any node is
+// considered — can only act on what the pod itself asked for; treating an
+// un-annotated pod as requiring alignment would wrongly park it whenever no
+// single zone fits, even though a best-effort/none node could legally spread
+// it across zones.
+func requiresNUMAAlignment(pod *v1.Pod) bool {
+	policyValue, ok := pod.Annotations[PolicyAnnotation]
+	if !ok {
+		return false
+	}
+	return policyValue == policyValueSingleNUMANode || policyValue == policyValueRestricted
+}
+
+// requestedForAlignmentCheck returns the resources a NUMA zone must hold for
+// pod, under the scope its annotation requested: the pod's total request in
+// pod scope (every container must share one zone), or its single largest
+// container's request in container scope (each container may pick its own
+// zone, so only the worst one constrains any given zone).
+func requestedForAlignmentCheck(pod *v1.Pod) v1.ResourceList {
+	if pod.Annotations[ScopeAnnotation] == scopeValueContainer {
+		return largestContainerRequests(pod)
+	}
+	return totalContainerRequests(pod)
+}
+
+// anyNodeFits reports whether nrt (restricted to zones matching tm's
+// required zone attribute, if any) could host pod. SingleNUMANode* demands
+// one zone hold the whole request, so only a single matching zone is
+// checked; Restricted* is legally allowed to span every zone the node
+// reports, so checking only a single zone there would make PreEnqueue
+// reject pods Filter would actually admit — it checks the zones' combined
+// capacity instead.
+func (tm *TopologyMatch) anyNodeFits(pod *v1.Pod, nrt *NodeResourceTopology) bool {
+	requested := requestedForAlignmentCheck(pod)
+	zones := tm.zonesMatchingRequiredAttribute(nodeScopedZones(nrt.Zones))
+
+	if pod.Annotations[PolicyAnnotation] == policyValueSingleNUMANode {
+		for _, zone := range zones {
+			if zoneFits(zone, requested) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return combinedZonesFit(zones, requested)
+}
+
+// zoneFits reports whether zone alone has enough of every resource in
+// requested.
+func zoneFits(zone Zone, requested v1.ResourceList) bool {
+	return fitsCapacity(requested, zoneCapacity(zone))
+}
+
+// combinedZonesFit reports whether zones, summed together, have enough of
+// every resource in requested — the Restricted policy's admission rule,
+// which may spread a request across every zone a node has.
+func combinedZonesFit(zones ZoneList, requested v1.ResourceList) bool {
+	capacity := v1.ResourceList{}
+	for _, zone := range zones {
+		for resName, qty := range zoneCapacity(zone) {
+			if existing, ok := capacity[resName]; ok {
+				existing.Add(qty)
+				capacity[resName] = existing
+			} else {
+				capacity[resName] = qty.DeepCopy()
+			}
+		}
+	}
+	return fitsCapacity(requested, capacity)
+}
+
+// fitsCapacity reports whether capacity has enough of every resource in
+// requested.
+func fitsCapacity(requested, capacity v1.ResourceList) bool {
+	for resName, reqQty := range requested {
+		availQty, ok := capacity[resName]
+		if !ok || reqQty.Cmp(availQty) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// isSchedulableAfterNRTChange is the QueueingHintFn for NodeResourceTopology
+// Add/Update events. A pod whose PolicyAnnotation requests NUMA alignment is
+// queued only when the added/updated NRT could host it, as anyNodeFits
+// judges; every other pod, and any event object that isn't a recognized NRT,
+// is queued.
+func (tm *TopologyMatch) isSchedulableAfterNRTChange(logger klog.Logger, pod *v1.Pod, oldObj, newObj interface{}) (framework.QueueingHint, error) {
+	if !requiresNUMAAlignment(pod) {
+		return framework.QueueingHintQueue, nil
+	}
+
+	nrt := canonicalNRTFromEvent(newObj)
+	if nrt == nil {
+		// unrecognized object: don't risk leaving the pod stuck
+		return framework.QueueingHintQueue, nil
+	}
+
+	if tm.anyNodeFits(pod, nrt) {
+		return framework.QueueingHintQueue, nil
+	}
+
+	return framework.QueueingHintSkip, nil
+}
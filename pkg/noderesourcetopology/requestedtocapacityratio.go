@@ -0,0 +1,222 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package noderesourcetopology
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	apiconfig "sigs.k8s.io/scheduler-plugins/apis/config"
+
+	topologyv1alpha1 "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha1"
+)
+
+// resourceScorer scores a single resource's utilization in one NUMA zone, on
+// the same 0-100 scale getScoringStrategyFunction's other strategies use.
+// available is the zone's pool-selected headroom (NUMANode.resourcesOf),
+// not its total Capacity: NUMANode never carries Capacity alongside
+// Available (combinationFits and subtractFromNUMAs are headroom-only too),
+// so utilization here is "how much of what's left would this request use up",
+// not "how much of the zone's full capacity is in use".
+type resourceScorer func(requested, available v1.ResourceList, resourceName v1.ResourceName) int64
+
+// validateRequestedToCapacityRatioShape rejects shapes the scorer cannot
+// interpolate over: points must be sorted by Utilization, unique, and both
+// coordinates must land in [0, 100].
+func validateRequestedToCapacityRatioShape(shape []apiconfig.UtilizationShapePoint) error {
+	if len(shape) < 2 {
+		return fmt.Errorf("requestedToCapacityRatio shape must have at least 2 points, got %d", len(shape))
+	}
+
+	for i, point := range shape {
+		if point.Utilization < 0 || point.Utilization > 100 {
+			return fmt.Errorf("utilization %d out of range [0, 100] at shape point %d", point.Utilization, i)
+		}
+		if point.Score < 0 || point.Score > 100 {
+			return fmt.Errorf("score %d out of range [0, 100] at shape point %d", point.Score, i)
+		}
+		if i == 0 {
+			continue
+		}
+		if point.Utilization <= shape[i-1].Utilization {
+			return fmt.Errorf("shape points must be sorted by strictly increasing utilization, point %d (%d) does not follow point %d (%d)",
+				i, point.Utilization, i-1, shape[i-1].Utilization)
+		}
+	}
+
+	return nil
+}
+
+// requestedToCapacityRatioScorer builds a resourceScorer that evaluates the
+// user-supplied shape: for utilization u it locates the two shape points
+// bracketing u and linearly interpolates between their scores.
+func requestedToCapacityRatioScorer(shape []apiconfig.UtilizationShapePoint) resourceScorer {
+	return func(requested, available v1.ResourceList, resourceName v1.ResourceName) int64 {
+		availQty, ok := available[resourceName]
+		if !ok || availQty.IsZero() {
+			return 0
+		}
+
+		reqQty := requested[resourceName]
+
+		utilization := reqQty.MilliValue() * 100 / availQty.MilliValue()
+		if utilization < 0 {
+			utilization = 0
+		}
+		if utilization > 100 {
+			utilization = 100
+		}
+
+		return interpolateShape(shape, utilization)
+	}
+}
+
+// interpolateShape returns the score shape assigns to utilization (0-100),
+// linearly interpolating between the two bracketing points.
+func interpolateShape(shape []apiconfig.UtilizationShapePoint, utilization int64) int64 {
+	if utilization <= int64(shape[0].Utilization) {
+		return int64(shape[0].Score)
+	}
+	last := len(shape) - 1
+	if utilization >= int64(shape[last].Utilization) {
+		return int64(shape[last].Score)
+	}
+
+	for i := 1; i <= last; i++ {
+		if utilization > int64(shape[i].Utilization) {
+			continue
+		}
+
+		lower, upper := shape[i-1], shape[i]
+		span := int64(upper.Utilization - lower.Utilization)
+		offset := utilization - int64(lower.Utilization)
+		return int64(lower.Score) + (int64(upper.Score-lower.Score)*offset)/span
+	}
+
+	// unreachable: utilization is within [shape[0], shape[last]]
+	return int64(shape[last].Score)
+}
+
+// newRequestedToCapacityRatioScoreHandlers builds the scoreHandlersMap for
+// the RequestedToCapacityRatio strategy: single-NUMA-node policies take the
+// min score across the node's zones, the rest take the mean. Every node zone
+// is scored, whether or not the request fits in it; zones are not narrowed
+// to the ones the pod would land on.
+func newRequestedToCapacityRatioScoreHandlers(shape []apiconfig.UtilizationShapePoint, weights resourceToWeightMap) scoreHandlersMap {
+	scorer := requestedToCapacityRatioScorer(shape)
+	minScore := requestedToCapacityRatioZoneScore(scorer, weights, aggregateMin)
+	meanScore := requestedToCapacityRatioZoneScore(scorer, weights, aggregateMean)
+
+	return scoreHandlersMap{
+		topologyv1alpha1.SingleNUMANodePodLevel:       minScore,
+		topologyv1alpha1.SingleNUMANodeContainerLevel: minScore,
+		topologyv1alpha1.BestEffortPodLevel:           meanScore,
+		topologyv1alpha1.BestEffortContainerLevel:     meanScore,
+		topologyv1alpha1.RestrictedPodLevel:           meanScore,
+		topologyv1alpha1.RestrictedContainerLevel:     meanScore,
+	}
+}
+
+// requestedToCapacityRatioZoneScore turns a per-resource resourceScorer into
+// a scoringFn: it weight-averages scorer's per-resource scores within each
+// zone's pool-selected headroom, then combines the per-zone scores with
+// aggregate.
+func requestedToCapacityRatioZoneScore(scorer resourceScorer, weights resourceToWeightMap, aggregate func([]int64) int64) scoringFn {
+	return func(pod *v1.Pod, zones ZoneList, pool resourcePool) (int64, *framework.Status) {
+		requested := totalContainerRequests(pod)
+		numaNodes := newNUMANodeList(nodeScopedZones(zones))
+
+		var zoneScores []int64
+		for _, node := range numaNodes {
+			zoneScores = append(zoneScores, weightedResourceScore(scorer, requested, node.resourcesOf(pool), weights))
+		}
+
+		if len(zoneScores) == 0 {
+			return 0, framework.NewStatus(framework.Unschedulable, "no NUMA zones reported for this node")
+		}
+
+		return aggregate(zoneScores), nil
+	}
+}
+
+// weightedResourceScore averages scorer's per-resource score over requested,
+// weighting each resource by resourceToWeightMap (defaulting to 1 for
+// resources the user didn't assign a weight to).
+func weightedResourceScore(scorer resourceScorer, requested, available v1.ResourceList, weights resourceToWeightMap) int64 {
+	var totalScore, totalWeight int64
+	for resName := range requested {
+		weight, ok := weights[resName]
+		if !ok {
+			weight = 1
+		}
+		totalScore += scorer(requested, available, resName) * weight
+		totalWeight += weight
+	}
+	if totalWeight == 0 {
+		return 0
+	}
+	return totalScore / totalWeight
+}
+
+// totalContainerRequests sums every container's resource requests into a
+// single ResourceList, the "requested" side of the per-zone utilization
+// formula.
+func totalContainerRequests(pod *v1.Pod) v1.ResourceList {
+	total := v1.ResourceList{}
+	for _, container := range pod.Spec.Containers {
+		for resName, qty := range container.Resources.Requests {
+			if existing, ok := total[resName]; ok {
+				existing.Add(qty)
+				total[resName] = existing
+			} else {
+				total[resName] = qty.DeepCopy()
+			}
+		}
+	}
+	return total
+}
+
+// zoneCapacity projects a Zone's resources into the v1.ResourceList shape
+// resourceScorer expects, using each resource's Available quantity as
+// capacity (the amount actually left for scheduling into).
+func zoneCapacity(zone Zone) v1.ResourceList {
+	capacity := make(v1.ResourceList, len(zone.Resources))
+	for _, res := range zone.Resources {
+		capacity[v1.ResourceName(res.Name)] = res.Available
+	}
+	return capacity
+}
+
+func aggregateMin(scores []int64) int64 {
+	min := scores[0]
+	for _, s := range scores[1:] {
+		if s < min {
+			min = s
+		}
+	}
+	return min
+}
+
+func aggregateMean(scores []int64) int64 {
+	var sum int64
+	for _, s := range scores {
+		sum += s
+	}
+	return sum / int64(len(scores))
+}
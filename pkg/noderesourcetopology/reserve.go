@@ -0,0 +1,60 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package noderesourcetopology
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+var _ framework.ReservePlugin = &TopologyMatch{}
+
+// Reserve accounts pod against the NUMA zones of nodeName, using the same
+// effective policy Filter/Score resolved for this pod, so a pod admitted
+// under an overridden policy is also booked against zones that policy
+// actually allows.
+func (tm *TopologyMatch) Reserve(ctx context.Context, cycleState *framework.CycleState, pod *v1.Pod, nodeName string) *framework.Status {
+	nrt, ok := tm.nrtCache.GetCachedNRTCopy(nodeName, pod)
+	if !ok || nrt == nil {
+		return nil
+	}
+
+	nodePolicy := nodeTopologyPolicy(nrt)
+	effectivePolicy, status := resolveEffectivePolicy(pod, nodePolicy, tm.allowStricterPodPolicy)
+	if status != nil {
+		return status
+	}
+
+	pool := qosResourcePool(pod, tm.qosResourcePoolRules)
+	if err := tm.nrtCache.ReserveNodeResources(nodeName, pod, effectivePolicy, pool); err != nil {
+		klog.ErrorS(err, "failed to reserve NUMA resources", "node", nodeName, "pod", klog.KObj(pod))
+		return framework.NewStatus(framework.Error, err.Error())
+	}
+
+	return nil
+}
+
+// Unreserve releases the accounting Reserve performed for pod on nodeName.
+// It must tolerate being called for a pod that never got past Reserve.
+func (tm *TopologyMatch) Unreserve(ctx context.Context, cycleState *framework.CycleState, pod *v1.Pod, nodeName string) {
+	if err := tm.nrtCache.UnreserveNodeResources(nodeName, pod); err != nil {
+		klog.ErrorS(err, "failed to unreserve NUMA resources", "node", nodeName, "pod", klog.KObj(pod))
+	}
+}
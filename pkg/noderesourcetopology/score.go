@@ -0,0 +1,60 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package noderesourcetopology
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+var _ framework.ScorePlugin = &TopologyMatch{}
+
+// Score ranks nodeName by how well its NodeResourceTopology fits pod under
+// the effective topology policy (the pod's own override, if any, resolved
+// against the node's policy).
+func (tm *TopologyMatch) Score(ctx context.Context, cycleState *framework.CycleState, pod *v1.Pod, nodeName string) (int64, *framework.Status) {
+	nrt, ok := tm.nrtCache.GetCachedNRTCopy(nodeName, pod)
+	if !ok || nrt == nil {
+		// no opinion: let other nodes compete on their own merits
+		return 0, nil
+	}
+
+	nodePolicy := nodeTopologyPolicy(nrt)
+	effectivePolicy, status := resolveEffectivePolicy(pod, nodePolicy, tm.allowStricterPodPolicy)
+	if status != nil {
+		// Filter should already have rejected nodeName in this case, but
+		// Score can run against nodes Filter didn't see (e.g. tests).
+		return 0, status
+	}
+
+	handler, ok := tm.scoringHandlers[effectivePolicy]
+	if !ok {
+		return 0, nil
+	}
+
+	pool := qosResourcePool(pod, tm.qosResourcePoolRules)
+	return handler(pod, tm.zonesMatchingRequiredAttribute(nrt.Zones), pool)
+}
+
+// ScoreExtensions returns the score extension interface; the scoring
+// strategies already normalize to the [MinNodeScore, MaxNodeScore] range, so
+// there is nothing left to do here.
+func (tm *TopologyMatch) ScoreExtensions() framework.ScoreExtensions {
+	return nil
+}
@@ -0,0 +1,223 @@
+/*
+Copyright 2021 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package noderesourcetopology
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	topologyv1alpha1 "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha1"
+	topologyv1alpha2 "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
+)
+
+// ZoneResourceInfo is one resource entry of a Zone, carried over verbatim
+// from whichever NRT API version populated it.
+type ZoneResourceInfo struct {
+	Name        string
+	Capacity    resource.Quantity
+	Allocatable resource.Quantity
+	Available   resource.Quantity
+}
+
+// Zone is this plugin's canonical, API-version-agnostic view of a NUMA
+// zone. v1alpha1 informers populate Resources/Name/Type only; v1alpha2
+// informers additionally populate Attributes and Costs.
+type Zone struct {
+	Name      string
+	Type      string
+	Resources []ZoneResourceInfo
+	// Attributes is free-form zone metadata (e.g. memory bandwidth, SMT
+	// policy, PCIe root) reported by v1alpha2. Nil for v1alpha1 zones.
+	Attributes map[string]string
+	// Costs maps another zone's name to the relative cost of spanning into
+	// it from this zone, reported by v1alpha2. Nil for v1alpha1 zones.
+	Costs map[string]int
+}
+
+// ZoneList is this plugin's canonical view of NodeResourceTopology.Zones.
+type ZoneList []Zone
+
+// NodeResourceTopology is this plugin's canonical, API-version-agnostic NRT.
+// nrtCache converts whichever of v1alpha1/v1alpha2 the cluster runs into
+// this shape, so Filter/Score/Reserve and PreEnqueue never branch on API
+// version.
+type NodeResourceTopology struct {
+	Name             string
+	TopologyPolicies []string
+	Zones            ZoneList
+}
+
+// fromV1alpha1 converts a v1alpha1 NodeResourceTopology into the canonical
+// shape. Attributes and Costs are left nil: v1alpha1 has no equivalent.
+func fromV1alpha1(nrt *topologyv1alpha1.NodeResourceTopology) *NodeResourceTopology {
+	if nrt == nil {
+		return nil
+	}
+
+	zones := make(ZoneList, 0, len(nrt.Zones))
+	for _, zone := range nrt.Zones {
+		resources := make([]ZoneResourceInfo, 0, len(zone.Resources))
+		for _, res := range zone.Resources {
+			resources = append(resources, ZoneResourceInfo{
+				Name:        res.Name,
+				Capacity:    res.Capacity,
+				Allocatable: res.Allocatable,
+				Available:   res.Available,
+			})
+		}
+		zones = append(zones, Zone{Name: zone.Name, Type: zone.Type, Resources: resources})
+	}
+
+	return &NodeResourceTopology{
+		Name:             nrt.Name,
+		TopologyPolicies: nrt.TopologyPolicies,
+		Zones:            zones,
+	}
+}
+
+// fromV1alpha2 converts a v1alpha2 NodeResourceTopology into the canonical
+// shape, carrying Attributes and Costs over where v1alpha1 has nothing to
+// offer.
+func fromV1alpha2(nrt *topologyv1alpha2.NodeResourceTopology) *NodeResourceTopology {
+	if nrt == nil {
+		return nil
+	}
+
+	zones := make(ZoneList, 0, len(nrt.Zones))
+	for _, zone := range nrt.Zones {
+		resources := make([]ZoneResourceInfo, 0, len(zone.Resources))
+		for _, res := range zone.Resources {
+			resources = append(resources, ZoneResourceInfo{
+				Name:        res.Name,
+				Capacity:    res.Capacity,
+				Allocatable: res.Allocatable,
+				Available:   res.Available,
+			})
+		}
+
+		var attributes map[string]string
+		if len(zone.Attributes) > 0 {
+			attributes = make(map[string]string, len(zone.Attributes))
+			for _, attr := range zone.Attributes {
+				attributes[attr.Name] = attr.Value
+			}
+		}
+
+		var costs map[string]int
+		if len(zone.Costs) > 0 {
+			costs = make(map[string]int, len(zone.Costs))
+			for _, cost := range zone.Costs {
+				costs[cost.Name] = int(cost.Value)
+			}
+		}
+
+		zones = append(zones, Zone{
+			Name:       zone.Name,
+			Type:       zone.Type,
+			Resources:  resources,
+			Attributes: attributes,
+			Costs:      costs,
+		})
+	}
+
+	return &NodeResourceTopology{
+		Name:             nrt.Name,
+		TopologyPolicies: policyNamesFromV1alpha2(nrt),
+		Zones:            zones,
+	}
+}
+
+// canonicalNRTFromEvent converts the raw object a NodeResourceTopology
+// informer event carries (either API version) into this plugin's canonical
+// shape, or returns nil if obj is neither.
+func canonicalNRTFromEvent(obj interface{}) *NodeResourceTopology {
+	switch o := obj.(type) {
+	case *topologyv1alpha1.NodeResourceTopology:
+		return fromV1alpha1(o)
+	case *topologyv1alpha2.NodeResourceTopology:
+		return fromV1alpha2(o)
+	default:
+		return nil
+	}
+}
+
+// crossZoneCost sums the pairwise v1alpha2 Costs between the zones in
+// zoneNames, as reported on zones. It is used by the least-NUMA scope
+// scorers to prefer, among zone sets that otherwise tie, the one with the
+// lowest aggregate cost when a best-effort/restricted pod must span more
+// than one zone. Zones with no Costs (v1alpha1, or a v1alpha2 zone that
+// simply doesn't report one) contribute zero, so this degrades to today's
+// pure zone-count comparison when cost data isn't available.
+func crossZoneCost(zones ZoneList, zoneNames []string) int {
+	byName := make(map[string]Zone, len(zones))
+	for _, zone := range zones {
+		byName[zone.Name] = zone
+	}
+
+	total := 0
+	for _, from := range zoneNames {
+		for _, to := range zoneNames {
+			if from == to {
+				continue
+			}
+			total += byName[from].Costs[to]
+		}
+	}
+	return total
+}
+
+// parseZoneAttributeMatch parses NodeResourceTopologyMatchArgs'
+// RequiredZoneAttribute (e.g. "snc=enabled") into the key/value pair a zone
+// must carry in its v1alpha2 Attributes to be considered for admission.
+func parseZoneAttributeMatch(s string) (key, value string, err error) {
+	k, v, ok := strings.Cut(s, "=")
+	if !ok || k == "" {
+		return "", "", fmt.Errorf("invalid requiredZoneAttribute %q, expected \"key=value\"", s)
+	}
+	return k, v, nil
+}
+
+// policyNamesFromV1alpha2 recovers the legacy TopologyPolicies string list
+// from a v1alpha2 NRT. v1alpha2 dropped that field in favor of two top-level
+// (not per-zone) Attributes: "topologyManagerPolicy" and
+// "topologyManagerScope", using the same kubelet-native values PolicyAnnotation
+// accepts, so they're combined the same way a pod annotation is.
+func policyNamesFromV1alpha2(nrt *topologyv1alpha2.NodeResourceTopology) []string {
+	var policyValue, scopeValue string
+	for _, attr := range nrt.Attributes {
+		switch attr.Name {
+		case "topologyManagerPolicy":
+			policyValue = attr.Value
+		case "topologyManagerScope":
+			scopeValue = attr.Value
+		}
+	}
+	if policyValue == "" {
+		return nil
+	}
+	if scopeValue == "" {
+		scopeValue = scopeValuePod
+	}
+
+	policy, err := policyFromAnnotationValues(policyValue, scopeValue)
+	if err != nil {
+		return nil
+	}
+	return []string{string(policy)}
+}